@@ -188,11 +188,18 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 			MetaClient: s.MetaClient,
 			TSDBStore:  coordinator.LocalTSDBStore{Store: s.TSDBStore},
 		},
-		Monitor:           s.Monitor,
-		PointsWriter:      s.PointsWriter,
-		MaxSelectPointN:   c.Coordinator.MaxSelectPointN,
-		MaxSelectSeriesN:  c.Coordinator.MaxSelectSeriesN,
-		MaxSelectBucketsN: c.Coordinator.MaxSelectBucketsN,
+		Monitor:             s.Monitor,
+		PointsWriter:        s.PointsWriter,
+		MaxSelectPointN:     c.Coordinator.MaxSelectPointN,
+		MaxSelectSeriesN:    c.Coordinator.MaxSelectSeriesN,
+		MaxSelectBucketsN:   c.Coordinator.MaxSelectBucketsN,
+		MaxSelectFieldsN:    c.Coordinator.MaxSelectFieldsN,
+		MaxSelectCallsN:     c.Coordinator.MaxSelectCallsN,
+		MaxSelectTopBottomN: c.Coordinator.MaxSelectTopBottomN,
+
+		EnforceRetentionTimeRange: c.Coordinator.EnforceRetentionTimeRange,
+		RejectExpiredTimeRange:    c.Coordinator.RejectExpiredTimeRange,
+		RejectEmptyTimeRange:      c.Coordinator.RejectEmptyTimeRange,
 	}
 	s.QueryExecutor.TaskManager.QueryTimeout = time.Duration(c.Coordinator.QueryTimeout)
 	s.QueryExecutor.TaskManager.LogQueriesAfter = time.Duration(c.Coordinator.LogQueriesAfter)
@@ -36,6 +36,13 @@ type Config struct {
 	MaxSelectPointN      int           `toml:"max-select-point"`
 	MaxSelectSeriesN     int           `toml:"max-select-series"`
 	MaxSelectBucketsN    int           `toml:"max-select-buckets"`
+	MaxSelectFieldsN     int           `toml:"max-select-fields"`
+	MaxSelectCallsN      int           `toml:"max-select-calls"`
+	MaxSelectTopBottomN  int           `toml:"max-select-top-bottom"`
+
+	EnforceRetentionTimeRange bool `toml:"enforce-retention-time-range"`
+	RejectExpiredTimeRange    bool `toml:"reject-expired-time-range"`
+	RejectEmptyTimeRange      bool `toml:"reject-empty-time-range"`
 }
 
 // NewConfig returns an instance of Config with defaults.
@@ -59,5 +66,12 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 		"max-select-point":       c.MaxSelectPointN,
 		"max-select-series":      c.MaxSelectSeriesN,
 		"max-select-buckets":     c.MaxSelectBucketsN,
+		"max-select-fields":      c.MaxSelectFieldsN,
+		"max-select-calls":       c.MaxSelectCallsN,
+		"max-select-top-bottom":  c.MaxSelectTopBottomN,
+
+		"enforce-retention-time-range": c.EnforceRetentionTimeRange,
+		"reject-expired-time-range":    c.RejectExpiredTimeRange,
+		"reject-empty-time-range":      c.RejectEmptyTimeRange,
 	}), nil
 }
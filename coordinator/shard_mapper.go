@@ -39,6 +39,12 @@ func (e *LocalShardMapper) MapShards(sources influxql.Sources, opt *influxql.Sel
 		ShardMap: make(map[Source]tsdb.ShardGroup),
 	}
 
+	// A time range that ends before it starts, such as
+	// time > now() AND time < now() - 1h, cannot match any points.
+	if opt.MinTime.After(opt.MaxTime) {
+		return a, nil
+	}
+
 	if err := e.mapShards(a, sources, opt); err != nil {
 		return nil, err
 	}
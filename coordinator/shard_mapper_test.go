@@ -100,3 +100,43 @@ func TestLocalShardMapper(t *testing.T) {
 		t.Fatalf("unexpected error: %s", err)
 	}
 }
+
+// Ensure no shards are looked up when the time range is empty.
+func TestLocalShardMapper_EmptyTimeRange(t *testing.T) {
+	var metaClient MetaClient
+	metaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) ([]meta.ShardGroupInfo, error) {
+		t.Fatal("unexpected call to ShardGroupsByTimeRange")
+		return nil, nil
+	}
+
+	var tsdbStore TSDBStore
+	tsdbStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+		t.Fatal("unexpected call to ShardGroup")
+		return nil
+	}
+
+	shardMapper := &coordinator.LocalShardMapper{
+		MetaClient: &metaClient,
+		TSDBStore:  &tsdbStore,
+	}
+
+	measurement := &influxql.Measurement{
+		Database:        "db0",
+		RetentionPolicy: "rp0",
+		Name:            "cpu",
+	}
+	now := time.Now()
+	ic, err := shardMapper.MapShards([]influxql.Source{measurement}, &influxql.SelectOptions{
+		MinTime: now,
+		MaxTime: now.Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if itr, err := ic.CreateIterator(measurement, influxql.IteratorOptions{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if itr != nil {
+		t.Fatalf("unexpected iterator: %#v", itr)
+	}
+}
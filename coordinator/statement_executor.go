@@ -45,9 +45,22 @@ type StatementExecutor struct {
 	PointsWriter pointsWriter
 
 	// Select statement limits
-	MaxSelectPointN   int
-	MaxSelectSeriesN  int
-	MaxSelectBucketsN int
+	MaxSelectPointN     int
+	MaxSelectSeriesN    int
+	MaxSelectBucketsN   int
+	MaxSelectFieldsN    int
+	MaxSelectCallsN     int
+	MaxSelectTopBottomN int
+
+	// Restricts the time range of a SELECT to the retention windows of its
+	// sources. When RejectExpiredTimeRange is set, queries that explicitly
+	// start before the window return an error instead of being clamped.
+	EnforceRetentionTimeRange bool
+	RejectExpiredTimeRange    bool
+
+	// Return an error for a SELECT whose time range ends before it starts
+	// instead of returning no results.
+	RejectEmptyTimeRange bool
 }
 
 // ExecuteStatement executes the given statement with the given execution context.
@@ -543,6 +556,36 @@ func (e *StatementExecutor) createIterators(stmt *influxql.SelectStatement, ctx
 		return nil, stmt, err
 	}
 
+	if e.RejectEmptyTimeRange && !opt.MaxTime.IsZero() && opt.MinTime.After(opt.MaxTime) {
+		return nil, stmt, fmt.Errorf("query time range is empty: %s is after %s", opt.MinTime.Format(time.RFC3339Nano), opt.MaxTime.Format(time.RFC3339Nano))
+	}
+
+	// Keep the query from reaching back past the retention policies of its sources.
+	if e.EnforceRetentionTimeRange {
+		if start, ok := e.retentionStartTime(stmt.Sources, now); ok && opt.MinTime.Before(start) {
+			if e.RejectExpiredTimeRange && !opt.MinTime.IsZero() {
+				return nil, stmt, fmt.Errorf("query time range starts before the retention window at %s", start.Format(time.RFC3339Nano))
+			}
+
+			// Add the new lower bound to the condition so the iterators use it too.
+			cond := &influxql.BinaryExpr{
+				Op:  influxql.GTE,
+				LHS: &influxql.VarRef{Val: "time"},
+				RHS: &influxql.TimeLiteral{Val: start},
+			}
+			if stmt.Condition != nil {
+				stmt.Condition = &influxql.BinaryExpr{
+					Op:  influxql.AND,
+					LHS: &influxql.ParenExpr{Expr: stmt.Condition},
+					RHS: cond,
+				}
+			} else {
+				stmt.Condition = cond
+			}
+			opt.MinTime = start
+		}
+	}
+
 	if opt.MaxTime.IsZero() {
 		opt.MaxTime = time.Unix(0, influxql.MaxTime)
 	}
@@ -578,6 +621,21 @@ func (e *StatementExecutor) createIterators(stmt *influxql.SelectStatement, ctx
 	}
 	stmt = tmp
 
+	// Check the field and function call limits once wildcards have been expanded.
+	if e.MaxSelectFieldsN > 0 && len(stmt.Fields) > e.MaxSelectFieldsN {
+		return nil, stmt, fmt.Errorf("max-select-fields limit exceeded: (%d/%d)", len(stmt.Fields), e.MaxSelectFieldsN)
+	}
+	if e.MaxSelectCallsN > 0 {
+		if n := stmt.TotalFunctionCallCount(); n > e.MaxSelectCallsN {
+			return nil, stmt, fmt.Errorf("max-select-calls limit exceeded: (%d/%d)", n, e.MaxSelectCallsN)
+		}
+	}
+	if e.MaxSelectTopBottomN > 0 {
+		if n := stmt.MaxTopBottomLimit(); n > int64(e.MaxSelectTopBottomN) {
+			return nil, stmt, fmt.Errorf("max-select-top-bottom limit exceeded: (%d/%d)", n, e.MaxSelectTopBottomN)
+		}
+	}
+
 	if e.MaxSelectBucketsN > 0 && !stmt.IsRawQuery {
 		interval, err := stmt.GroupByInterval()
 		if err != nil {
@@ -667,6 +725,10 @@ func (e *StatementExecutor) executeShowDiagnosticsStatement(stmt *influxql.ShowD
 }
 
 func (e *StatementExecutor) executeShowGrantsForUserStatement(q *influxql.ShowGrantsForUserStatement) (models.Rows, error) {
+	if q.Name == "" {
+		return nil, meta.ErrUsernameRequired
+	}
+
 	priv, err := e.MetaClient.UserPrivileges(q.Name)
 	if err != nil {
 		return nil, err
@@ -1141,6 +1203,40 @@ func (e *StatementExecutor) normalizeMeasurement(m *influxql.Measurement, defaul
 	return nil
 }
 
+// retentionStartTime returns the oldest time still kept by the retention
+// policies of every measurement in sources. It returns false if any of them
+// keeps data forever or cannot be found.
+func (e *StatementExecutor) retentionStartTime(sources influxql.Sources, now time.Time) (time.Time, bool) {
+	var start time.Time
+	for _, src := range sources {
+		var t time.Time
+		switch src := src.(type) {
+		case *influxql.Measurement:
+			di := e.MetaClient.Database(src.Database)
+			if di == nil {
+				return time.Time{}, false
+			}
+			rpi := di.RetentionPolicy(src.RetentionPolicy)
+			if rpi == nil || rpi.Duration == 0 {
+				return time.Time{}, false
+			}
+			t = now.Add(-rpi.Duration)
+		case *influxql.SubQuery:
+			var ok bool
+			if t, ok = e.retentionStartTime(src.Statement.Sources, now); !ok {
+				return time.Time{}, false
+			}
+		default:
+			continue
+		}
+
+		if start.IsZero() || t.Before(start) {
+			start = t
+		}
+	}
+	return start, !start.IsZero()
+}
+
 // IntoWriteRequest is a partial copy of cluster.WriteRequest
 type IntoWriteRequest struct {
 	Database        string
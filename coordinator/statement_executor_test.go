@@ -7,6 +7,7 @@ import (
 	"os"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -127,6 +128,375 @@ func TestQueryExecutor_ExecuteQuery_MaxSelectBucketsN(t *testing.T) {
 	}
 }
 
+// Ensure query executor can enforce a maximum field count.
+func TestQueryExecutor_ExecuteQuery_MaxSelectFieldsN(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.StatementExecutor.MaxSelectFieldsN = 2
+
+	// The meta client should return a single shards on the local node.
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+		return []meta.ShardGroupInfo{
+			{ID: 1, Shards: []meta.ShardInfo{
+				{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}},
+			}},
+		}, nil
+	}
+
+	e.TSDBStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+		if !reflect.DeepEqual(ids, []uint64{100}) {
+			t.Fatalf("unexpected shard ids: %v", ids)
+		}
+
+		var sh MockShard
+		sh.CreateIteratorFn = func(m string, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			t.Fatal("unexpected call to CreateIterator")
+			return nil, nil
+		}
+		sh.FieldDimensionsFn = func(measurements []string) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error) {
+			if !reflect.DeepEqual(measurements, []string{"cpu"}) {
+				t.Fatalf("unexpected source: %#v", measurements)
+			}
+			return map[string]influxql.DataType{"value": influxql.Float}, nil, nil
+		}
+		return &sh
+	}
+
+	// Verify all results from the query.
+	if a := ReadAllResults(e.ExecuteQuery(`SELECT value, value * 2, value * 3 FROM cpu`, "db0", 0)); !reflect.DeepEqual(a, []*influxql.Result{
+		{
+			StatementID: 0,
+			Err:         errors.New("max-select-fields limit exceeded: (3/2)"),
+		},
+	}) {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure query executor can enforce a maximum function call count.
+func TestQueryExecutor_ExecuteQuery_MaxSelectCallsN(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.StatementExecutor.MaxSelectCallsN = 1
+
+	// The meta client should return a single shards on the local node.
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+		return []meta.ShardGroupInfo{
+			{ID: 1, Shards: []meta.ShardInfo{
+				{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}},
+			}},
+		}, nil
+	}
+
+	e.TSDBStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+		if !reflect.DeepEqual(ids, []uint64{100}) {
+			t.Fatalf("unexpected shard ids: %v", ids)
+		}
+
+		var sh MockShard
+		sh.CreateIteratorFn = func(m string, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			t.Fatal("unexpected call to CreateIterator")
+			return nil, nil
+		}
+		sh.FieldDimensionsFn = func(measurements []string) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error) {
+			if !reflect.DeepEqual(measurements, []string{"cpu"}) {
+				t.Fatalf("unexpected source: %#v", measurements)
+			}
+			return map[string]influxql.DataType{"value": influxql.Float}, nil, nil
+		}
+		return &sh
+	}
+
+	// Verify all results from the query.
+	if a := ReadAllResults(e.ExecuteQuery(`SELECT count(value), sum(value) FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:01:00Z' GROUP BY time(10s)`, "db0", 0)); !reflect.DeepEqual(a, []*influxql.Result{
+		{
+			StatementID: 0,
+			Err:         errors.New("max-select-calls limit exceeded: (2/1)"),
+		},
+	}) {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure query executor allows a top() limit equal to the maximum.
+func TestQueryExecutor_ExecuteQuery_MaxSelectTopBottomN_AtLimit(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.StatementExecutor.MaxSelectTopBottomN = 2
+
+	// The meta client should return a single shards on the local node.
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+		return []meta.ShardGroupInfo{
+			{ID: 1, Shards: []meta.ShardInfo{
+				{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}},
+			}},
+		}, nil
+	}
+
+	e.TSDBStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+		var sh MockShard
+		sh.CreateIteratorFn = func(m string, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			return &FloatIterator{Points: []influxql.FloatPoint{
+				{Name: "cpu", Time: int64(0 * time.Second), Value: 100},
+				{Name: "cpu", Time: int64(1 * time.Second), Value: 300},
+				{Name: "cpu", Time: int64(2 * time.Second), Value: 200},
+			}}, nil
+		}
+		sh.FieldDimensionsFn = func(measurements []string) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error) {
+			return map[string]influxql.DataType{"value": influxql.Float}, nil, nil
+		}
+		return &sh
+	}
+
+	// Verify all results from the query.
+	if a := ReadAllResults(e.ExecuteQuery(`SELECT top(value, 2) FROM cpu`, "db0", 0)); !reflect.DeepEqual(a, []*influxql.Result{
+		{
+			StatementID: 0,
+			Series: []*models.Row{{
+				Name:    "cpu",
+				Columns: []string{"time", "top"},
+				Values: [][]interface{}{
+					{time.Unix(1, 0).UTC(), float64(300)},
+					{time.Unix(2, 0).UTC(), float64(200)},
+				},
+			}},
+		},
+	}) {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure query executor can enforce a maximum top() and bottom() limit.
+func TestQueryExecutor_ExecuteQuery_MaxSelectTopBottomN(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.StatementExecutor.MaxSelectTopBottomN = 2
+
+	// The meta client should return a single shards on the local node.
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+		return []meta.ShardGroupInfo{
+			{ID: 1, Shards: []meta.ShardInfo{
+				{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}},
+			}},
+		}, nil
+	}
+
+	e.TSDBStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+		var sh MockShard
+		sh.CreateIteratorFn = func(m string, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			t.Fatal("unexpected call to CreateIterator")
+			return nil, nil
+		}
+		sh.FieldDimensionsFn = func(measurements []string) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error) {
+			return map[string]influxql.DataType{"value": influxql.Float}, nil, nil
+		}
+		return &sh
+	}
+
+	// Verify all results from the query.
+	if a := ReadAllResults(e.ExecuteQuery(`SELECT value FROM (SELECT bottom(value, 3) AS value FROM cpu)`, "db0", 0)); !reflect.DeepEqual(a, []*influxql.Result{
+		{
+			StatementID: 0,
+			Err:         errors.New("max-select-top-bottom limit exceeded: (3/2)"),
+		},
+	}) {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure query executor does not look up shards for a time range that ends before it starts.
+func TestQueryExecutor_ExecuteQuery_EmptyTimeRange(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+		t.Fatal("unexpected call to ShardGroupsByTimeRange")
+		return nil, nil
+	}
+
+	if a := ReadAllResults(e.ExecuteQuery(`SELECT value FROM cpu WHERE time > now() AND time < now() - 1h`, "db0", 0)); len(a) != 1 || a[0].Err != nil || len(a[0].Series) != 0 {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure query executor can reject a time range that ends before it starts.
+func TestQueryExecutor_ExecuteQuery_RejectEmptyTimeRange(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.StatementExecutor.RejectEmptyTimeRange = true
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+		t.Fatal("unexpected call to ShardGroupsByTimeRange")
+		return nil, nil
+	}
+
+	if a := ReadAllResults(e.ExecuteQuery(`SELECT value FROM cpu WHERE time >= '2000-01-01T01:00:00Z' AND time < '2000-01-01T00:00:00Z'`, "db0", 0)); !reflect.DeepEqual(a, []*influxql.Result{
+		{
+			StatementID: 0,
+			Err:         errors.New("query time range is empty: 2000-01-01T01:00:00Z is after 1999-12-31T23:59:59.999999999Z"),
+		},
+	}) {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure query executor moves the start of the time range up to the retention window.
+func TestQueryExecutor_ExecuteQuery_EnforceRetentionTimeRange(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.StatementExecutor.EnforceRetentionTimeRange = true
+	e.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{
+			Name:                   DefaultDatabase,
+			DefaultRetentionPolicy: DefaultRetentionPolicy,
+			RetentionPolicies: []meta.RetentionPolicyInfo{
+				{Name: DefaultRetentionPolicy, Duration: time.Hour},
+			},
+		}
+	}
+
+	// The oldest time the query may use is an hour before it runs.
+	earliest := time.Now().Add(-time.Hour)
+
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+		if min.Before(earliest) {
+			t.Errorf("unexpected min time: %s", min)
+		}
+		return []meta.ShardGroupInfo{
+			{ID: 1, Shards: []meta.ShardInfo{
+				{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}},
+			}},
+		}, nil
+	}
+
+	e.TSDBStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+		var sh MockShard
+		sh.CreateIteratorFn = func(m string, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			if opt.StartTime < earliest.UnixNano() {
+				t.Errorf("unexpected start time: %s", time.Unix(0, opt.StartTime).UTC())
+			}
+			return &FloatIterator{}, nil
+		}
+		sh.FieldDimensionsFn = func(measurements []string) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error) {
+			return map[string]influxql.DataType{"value": influxql.Float}, nil, nil
+		}
+		return &sh
+	}
+
+	if a := ReadAllResults(e.ExecuteQuery(`SELECT value FROM cpu WHERE time >= now() - 2h`, "db0", 0)); len(a) != 1 || a[0].Err != nil {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure the retention window is applied to unbounded queries and subqueries
+// but not to retention policies that keep data forever.
+func TestQueryExecutor_ExecuteQuery_EnforceRetentionTimeRange_Sources(t *testing.T) {
+	for i, tt := range []struct {
+		q        string
+		duration time.Duration
+		clamped  bool
+	}{
+		{q: `SELECT value FROM cpu`, duration: time.Hour, clamped: true},
+		{q: `SELECT value FROM (SELECT value FROM cpu)`, duration: time.Hour, clamped: true},
+		{q: `SELECT value FROM cpu`, duration: 0, clamped: false},
+		{q: `SELECT value FROM (SELECT value FROM cpu)`, duration: 0, clamped: false},
+	} {
+		e := DefaultQueryExecutor()
+		e.StatementExecutor.EnforceRetentionTimeRange = true
+		e.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+			return &meta.DatabaseInfo{
+				Name:                   DefaultDatabase,
+				DefaultRetentionPolicy: DefaultRetentionPolicy,
+				RetentionPolicies: []meta.RetentionPolicyInfo{
+					{Name: DefaultRetentionPolicy, Duration: tt.duration},
+				},
+			}
+		}
+
+		// Without a retention window the query should start at the beginning of time.
+		earliest := time.Unix(0, influxql.MinTime).UTC()
+		if tt.clamped {
+			earliest = time.Now().Add(-tt.duration)
+		}
+
+		var minTime time.Time
+		var startTime int64
+		e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+			minTime = min
+			return []meta.ShardGroupInfo{
+				{ID: 1, Shards: []meta.ShardInfo{
+					{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}},
+				}},
+			}, nil
+		}
+		e.TSDBStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+			var sh MockShard
+			sh.CreateIteratorFn = func(m string, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+				startTime = opt.StartTime
+				return &FloatIterator{}, nil
+			}
+			sh.FieldDimensionsFn = func(measurements []string) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error) {
+				return map[string]influxql.DataType{"value": influxql.Float}, nil, nil
+			}
+			return &sh
+		}
+
+		if a := ReadAllResults(e.ExecuteQuery(tt.q, "db0", 0)); len(a) != 1 || a[0].Err != nil {
+			t.Fatalf("%d. %s: unexpected results: %s", i, tt.q, spew.Sdump(a))
+		}
+
+		if tt.clamped {
+			if minTime.Before(earliest) || minTime.After(earliest.Add(time.Minute)) {
+				t.Errorf("%d. %s: unexpected min time: %s", i, tt.q, minTime)
+			} else if startTime < earliest.UnixNano() {
+				t.Errorf("%d. %s: unexpected start time: %s", i, tt.q, time.Unix(0, startTime).UTC())
+			}
+		} else {
+			if !minTime.Equal(earliest) {
+				t.Errorf("%d. %s: unexpected min time: %s", i, tt.q, minTime)
+			} else if startTime != influxql.MinTime {
+				t.Errorf("%d. %s: unexpected start time: %s", i, tt.q, time.Unix(0, startTime).UTC())
+			}
+		}
+	}
+}
+
+// Ensure query executor can reject a time range that starts before the retention window.
+func TestQueryExecutor_ExecuteQuery_RejectExpiredTimeRange(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.StatementExecutor.EnforceRetentionTimeRange = true
+	e.StatementExecutor.RejectExpiredTimeRange = true
+	e.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{
+			Name:                   DefaultDatabase,
+			DefaultRetentionPolicy: DefaultRetentionPolicy,
+			RetentionPolicies: []meta.RetentionPolicyInfo{
+				{Name: DefaultRetentionPolicy, Duration: time.Hour},
+			},
+		}
+	}
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+		t.Fatal("unexpected call to ShardGroupsByTimeRange")
+		return nil, nil
+	}
+
+	a := ReadAllResults(e.ExecuteQuery(`SELECT value FROM cpu WHERE time >= now() - 2h`, "db0", 0))
+	if len(a) != 1 || a[0].Err == nil {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	} else if !strings.HasPrefix(a[0].Err.Error(), "query time range starts before the retention window at ") {
+		t.Fatalf("unexpected error: %s", a[0].Err)
+	}
+}
+
+// Ensure SHOW GRANTS requires a user name.
+func TestQueryExecutor_ExecuteQuery_ShowGrantsForUser_EmptyName(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.MetaClient.UserPrivilegesFn = func(username string) (map[string]influxql.Privilege, error) {
+		t.Fatal("unexpected call to UserPrivileges")
+		return nil, nil
+	}
+
+	if a := ReadAllResults(e.ExecuteQuery(`SHOW GRANTS FOR ""`, "db0", 0)); !reflect.DeepEqual(a, []*influxql.Result{
+		{
+			StatementID: 0,
+			Err:         errors.New("username required"),
+		},
+	}) {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+}
+
 func TestStatementExecutor_NormalizeDropSeries(t *testing.T) {
 	q, err := influxql.ParseQuery("DROP SERIES FROM cpu")
 	if err != nil {
@@ -1136,7 +1136,8 @@ func cloneSource(s Source) Source {
 // RewriteFields returns the re-written form of the select statement. Any wildcard query
 // fields are replaced with the supplied fields, and any wildcard GROUP BY fields are replaced
 // with the supplied dimensions. Any fields with no type specifier are rewritten with the
-// appropriate type.
+// appropriate type. An error is returned if the GROUP BY clause references a field
+// or if a numeric fill value is used with a selector on a string or boolean field.
 func (s *SelectStatement) RewriteFields(m FieldMapper) (*SelectStatement, error) {
 	// Clone the statement so we aren't rewriting the original.
 	other := s.Clone()
@@ -1170,6 +1171,55 @@ func (s *SelectStatement) RewriteFields(m FieldMapper) (*SelectStatement, error)
 	WalkFunc(other.Fields, rewrite)
 	WalkFunc(other.Condition, rewrite)
 
+	// Selectors return values of the field type, so a numeric fill value
+	// cannot be used with a named field known to be a string or boolean.
+	// Fields expanded from wildcards below are not checked and keep being
+	// filled with the zero value of their type.
+	if other.Fill == NumberFill {
+		if interval, err := other.GroupByInterval(); err == nil && interval > 0 {
+			var fillErr error
+			WalkFunc(other.Fields, func(n Node) {
+				call, ok := n.(*Call)
+				if !ok || fillErr != nil || len(call.Args) == 0 {
+					return
+				}
+				switch call.Name {
+				case "min", "max", "first", "last", "mode":
+					if ref, ok := call.Args[0].(*VarRef); ok && (ref.Type == String || ref.Type == Boolean) {
+						fillErr = fmt.Errorf("cannot use fill(%v) with %s on field of type %s: %s", other.FillValue, call.Name, ref.Type, ref.Val)
+					}
+				}
+			})
+			if fillErr != nil {
+				return nil, fillErr
+			}
+		}
+	}
+
+	// Ensure nothing in the GROUP BY refers to a field. The type mapper
+	// reports a field type when a name is both a field and a tag, so only
+	// then look up the tags to see if the name is also one of them.
+	var dimensionSet map[string]struct{}
+	for _, d := range other.Dimensions {
+		ref, ok := d.Expr.(*VarRef)
+		if !ok {
+			continue
+		} else if typ := EvalType(&VarRef{Val: ref.Val}, other.Sources, m); typ == Tag || typ == Unknown {
+			continue
+		}
+
+		if dimensionSet == nil {
+			_, dims, err := FieldDimensions(other.Sources, m)
+			if err != nil {
+				return nil, err
+			}
+			dimensionSet = dims
+		}
+		if _, ok := dimensionSet[ref.Val]; !ok {
+			return nil, errors.New("can only group by tags and time")
+		}
+	}
+
 	// Ignore if there are no wildcards.
 	hasFieldWildcard := other.HasFieldWildcard()
 	hasDimensionWildcard := other.HasDimensionWildcard()
@@ -1326,6 +1376,12 @@ func (s *SelectStatement) RewriteFields(m FieldMapper) (*SelectStatement, error)
 			}
 		}
 		other.Fields = rwFields
+
+		// A wildcard may have expanded distinct() into multiple calls. That
+		// is no more valid than writing each of those calls out by hand.
+		if err := other.validateDistinct(); err != nil {
+			return nil, err
+		}
 	}
 
 	// Rewrite all wildcard GROUP BY fields
@@ -1806,6 +1862,8 @@ func (s *SelectStatement) validateDimensions() error {
 				return errors.New("time dimension expected 1 or 2 arguments")
 			} else if lit, ok := expr.Args[0].(*DurationLiteral); !ok {
 				return errors.New("time dimension must have duration argument")
+			} else if lit.Val == 0 {
+				return errors.New("time dimension must have non-zero duration")
 			} else if dur != 0 {
 				return errors.New("multiple time dimensions not allowed")
 			} else {
@@ -1898,6 +1956,15 @@ func (s *SelectStatement) validTopBottomAggr(expr *Call) error {
 			return fmt.Errorf("limit (%d) in %s function can not be larger than the LIMIT (%d) in the select statement", int64(callLimit.Val), expr.Name, int64(s.Limit))
 		}
 
+		if call, ok := expr.Args[0].(*Call); ok {
+			// Other calls, such as now(), fall through to the field check below.
+			switch call.Name {
+			case "count", "distinct", "integral", "mean", "median", "mode", "spread", "stddev", "sum",
+				"first", "last", "min", "max", "percentile", "sample", "top", "bottom":
+				return fmt.Errorf("nested aggregate %s is not allowed in %s()", call.Name, expr.Name)
+			}
+		}
+
 		for _, v := range expr.Args[:len(expr.Args)-1] {
 			if _, ok := v.(*VarRef); !ok {
 				return fmt.Errorf("only fields or tags are allowed in %s(), found %s", expr.Name, v)
@@ -1923,15 +1990,11 @@ func (s *SelectStatement) validPercentileAggr(expr *Call) error {
 		return fmt.Errorf("expected field argument in percentile()")
 	}
 
-	switch expr.Args[1].(type) {
-	case *IntegerLiteral, *NumberLiteral:
-		return nil
-	default:
-		return fmt.Errorf("expected float argument in percentile()")
-	}
+	_, err := numericArg(expr, 1, true)
+	return err
 }
 
-// validPercentileAggr determines if the call to SAMPLE has valid arguments.
+// validSampleAggr determines if the call to SAMPLE has valid arguments.
 func (s *SelectStatement) validSampleAggr(expr *Call) error {
 	if err := s.validSelectWithAggregate(); err != nil {
 		return err
@@ -1947,12 +2010,26 @@ func (s *SelectStatement) validSampleAggr(expr *Call) error {
 		return fmt.Errorf("expected field argument in sample()")
 	}
 
-	switch expr.Args[1].(type) {
+	_, err := numericArg(expr, 1, false)
+	return err
+}
+
+// numericArg returns the value of the numeric literal at position idx of the
+// call's arguments. Float literals are only accepted when allowFloat is set.
+func numericArg(expr *Call, idx int, allowFloat bool) (float64, error) {
+	switch arg := expr.Args[idx].(type) {
 	case *IntegerLiteral:
-		return nil
-	default:
-		return fmt.Errorf("expected integer argument in sample()")
+		return float64(arg.Val), nil
+	case *NumberLiteral:
+		if allowFloat {
+			return arg.Val, nil
+		}
+	}
+
+	if allowFloat {
+		return 0, fmt.Errorf("expected float argument in %s()", expr.Name)
 	}
+	return 0, fmt.Errorf("expected integer argument in %s()", expr.Name)
 }
 
 func (s *SelectStatement) validateAggregates(tr targetRequirement) error {
@@ -1984,7 +2061,9 @@ func (s *SelectStatement) validateAggregates(tr targetRequirement) error {
 						return fmt.Errorf("invalid number of arguments for moving_average, expected 2, got %d", got)
 					}
 
-					if lit, ok := expr.Args[1].(*IntegerLiteral); !ok {
+					if _, ok := expr.Args[1].(*DurationLiteral); ok {
+						return errors.New("moving_average window must be an integer number of points")
+					} else if lit, ok := expr.Args[1].(*IntegerLiteral); !ok {
 						return fmt.Errorf("second argument for moving_average must be an integer, got %T", expr.Args[1])
 					} else if lit.Val <= 1 {
 						return fmt.Errorf("moving_average window must be greater than 1, got %d", lit.Val)
@@ -2012,6 +2091,8 @@ func (s *SelectStatement) validateAggregates(tr targetRequirement) error {
 						if err := s.validPercentileAggr(c); err != nil {
 							return err
 						}
+					case "derivative", "non_negative_derivative", "difference", "non_negative_difference", "moving_average", "cumulative_sum", "elapsed":
+						return fmt.Errorf("nested transformation %s is not allowed in %s()", c.Name, expr.Name)
 					default:
 						if exp, got := 1, len(c.Args); got != exp {
 							return fmt.Errorf("invalid number of arguments for %s, expected %d, got %d", c.Name, exp, got)
@@ -2328,9 +2409,27 @@ func (s *SelectStatement) GroupByOffset() (time.Duration, error) {
 			if len(call.Args) == 2 {
 				switch expr := call.Args[1].(type) {
 				case *DurationLiteral:
-					return expr.Val % interval, nil
+					// Normalize the offset into the interval. A negative offset
+					// is the same as counting back from the end of the interval.
+					offset := expr.Val % interval
+					if offset < 0 {
+						offset += interval
+					}
+					return offset, nil
 				case *TimeLiteral:
-					return expr.Val.Sub(expr.Val.Truncate(interval)), nil
+					// Use the distance of the time from the start of its
+					// interval so the time falls on a bucket boundary. Buckets
+					// are aligned to the epoch in the statement's time zone.
+					t := expr.Val.UnixNano()
+					if s.Location != nil {
+						_, zone := expr.Val.In(s.Location).Zone()
+						t += int64(zone) * int64(time.Second)
+					}
+					offset := time.Duration(t % int64(interval))
+					if offset < 0 {
+						offset += interval
+					}
+					return offset, nil
 				default:
 					return 0, fmt.Errorf("invalid time dimension offset: %s", expr)
 				}
@@ -2519,7 +2618,44 @@ func (s *SelectStatement) FunctionCallsByPosition() [][]*Call {
 	return a
 }
 
-// walkFunctionCalls walks the Expr and returns any function calls made.
+// TotalFunctionCallCount returns the number of function calls in the statement,
+// including nested calls and the calls made by any subqueries.
+func (s *SelectStatement) TotalFunctionCallCount() int {
+	var n int
+	WalkFunc(s.Fields, func(node Node) {
+		if _, ok := node.(*Call); ok {
+			n++
+		}
+	})
+	for _, src := range s.Sources {
+		if src, ok := src.(*SubQuery); ok {
+			n += src.Statement.TotalFunctionCallCount()
+		}
+	}
+	return n
+}
+
+// MaxTopBottomLimit returns the largest number of points requested by any
+// top() or bottom() call in the statement or its subqueries.
+func (s *SelectStatement) MaxTopBottomLimit() int64 {
+	var max int64
+	WalkFunc(s.Fields, func(node Node) {
+		if call, ok := node.(*Call); ok && (call.Name == "top" || call.Name == "bottom") && len(call.Args) > 0 {
+			if lit, ok := call.Args[len(call.Args)-1].(*IntegerLiteral); ok && lit.Val > max {
+				max = lit.Val
+			}
+		}
+	})
+	for _, src := range s.Sources {
+		if src, ok := src.(*SubQuery); ok {
+			if n := src.Statement.MaxTopBottomLimit(); n > max {
+				max = n
+			}
+		}
+	}
+	return max
+}
+
 func walkFunctionCalls(exp Expr) []*Call {
 	switch expr := exp.(type) {
 	case *VarRef:
@@ -129,6 +129,51 @@ func TestSelectStatement_GroupByInterval(t *testing.T) {
 	}
 }
 
+// Ensure the SELECT statement can extract GROUP BY offset.
+func TestSelectStatement_GroupByOffset(t *testing.T) {
+	for i, tt := range []struct {
+		q   string
+		now time.Time
+		exp time.Duration
+	}{
+		{q: `SELECT sum(value) FROM foo WHERE time < now() GROUP BY time(1h)`, exp: 0},
+		{q: `SELECT sum(value) FROM foo WHERE time < now() GROUP BY time(1h, 10m)`, exp: 10 * time.Minute},
+		{q: `SELECT sum(value) FROM foo WHERE time < now() GROUP BY time(1h, 70m)`, exp: 10 * time.Minute},
+		{q: `SELECT sum(value) FROM foo WHERE time < now() GROUP BY time(1h, -10m)`, exp: 50 * time.Minute},
+		{q: `SELECT sum(value) FROM foo WHERE time < now() GROUP BY time(1h, -70m)`, exp: 50 * time.Minute},
+		{
+			q:   `SELECT sum(value) FROM foo WHERE time < now() GROUP BY time(1h, now())`,
+			now: mustParseTime("2000-01-01T02:10:00Z"),
+			exp: 10 * time.Minute,
+		},
+		{
+			q:   `SELECT sum(value) FROM foo WHERE time < now() GROUP BY time(7d, now())`,
+			now: mustParseTime("2000-01-03T00:00:00Z"),
+			exp: 4 * 24 * time.Hour,
+		},
+		{
+			q:   `SELECT sum(value) FROM foo WHERE time < now() GROUP BY time(1d, now()) tz('America/Chicago')`,
+			now: mustParseTime("2000-01-01T08:00:00Z"),
+			exp: 2 * time.Hour,
+		},
+	} {
+		stmt, err := influxql.NewParser(strings.NewReader(tt.q)).ParseStatement()
+		if err != nil {
+			t.Fatalf("%d. invalid statement: %q: %s", i, tt.q, err)
+		}
+		if !tt.now.IsZero() {
+			stmt = stmt.(*influxql.SelectStatement).Reduce(&influxql.NowValuer{Now: tt.now})
+		}
+
+		offset, err := stmt.(*influxql.SelectStatement).GroupByOffset()
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, tt.q, err)
+		} else if offset != tt.exp {
+			t.Errorf("%d. %q: group by offset not equal:\nexp=%s\ngot=%s", i, tt.q, tt.exp, offset)
+		}
+	}
+}
+
 // Ensure the SELECT statement can have its start and end time set
 func TestSelectStatement_SetTimeRange(t *testing.T) {
 	q := "SELECT sum(value) from foo where time < now() GROUP BY time(10m)"
@@ -335,6 +380,17 @@ func TestSelectStatement_RewriteFields(t *testing.T) {
 			rewrite: `SELECT value FROM cpu`,
 		},
 
+		// Explicit tag and field qualifiers
+		{
+			stmt:    `SELECT host::tag, value1::field FROM cpu`,
+			rewrite: `SELECT host::tag, value1::float FROM cpu`,
+		},
+
+		{
+			stmt:    `SELECT host::field FROM cpu`,
+			rewrite: `SELECT host::field FROM cpu`,
+		},
+
 		// Query wildcard
 		{
 			stmt:    `SELECT * FROM cpu`,
@@ -361,6 +417,53 @@ func TestSelectStatement_RewriteFields(t *testing.T) {
 			rewrite: `SELECT region::tag, value1::float, value2::integer FROM cpu GROUP BY host`,
 		},
 
+		// GROUP BY a field
+		{
+			stmt: `SELECT mean(value1) FROM cpu GROUP BY value2`,
+			err:  `can only group by tags and time`,
+		},
+
+		{
+			stmt: `SELECT value FROM (SELECT mean(value1) AS value FROM cpu GROUP BY host) GROUP BY value`,
+			err:  `can only group by tags and time`,
+		},
+
+		{
+			stmt:    `SELECT value FROM (SELECT mean(value1) AS value FROM cpu GROUP BY host) GROUP BY host`,
+			rewrite: `SELECT value::float FROM (SELECT mean(value1::float) AS value FROM cpu GROUP BY host) GROUP BY host`,
+		},
+
+		{
+			stmt:    `SELECT mean(value) FROM overlap GROUP BY host`,
+			rewrite: `SELECT mean(value::float) FROM overlap GROUP BY host`,
+		},
+
+		// Numeric fill with selectors on non-numeric fields
+		{
+			stmt: `SELECT last(string) FROM strings WHERE time < now() GROUP BY time(10s) fill(0)`,
+			err:  `cannot use fill(0) with last on field of type string: string`,
+		},
+
+		{
+			stmt: `SELECT first(bool) FROM bools WHERE time < now() GROUP BY time(10s) fill(1)`,
+			err:  `cannot use fill(1) with first on field of type boolean: bool`,
+		},
+
+		{
+			stmt:    `SELECT last(unknown) FROM strings WHERE time < now() GROUP BY time(10s) fill(0)`,
+			rewrite: `SELECT last(unknown) FROM strings WHERE time < now() GROUP BY time(10s) fill(0)`,
+		},
+
+		{
+			stmt:    `SELECT last(string) FROM strings WHERE time < now() GROUP BY time(10s) fill(null)`,
+			rewrite: `SELECT last(string::string) FROM strings WHERE time < now() GROUP BY time(10s)`,
+		},
+
+		{
+			stmt:    `SELECT first(*) FROM strings WHERE time < now() GROUP BY time(10s) fill(0)`,
+			rewrite: `SELECT first(string::string) AS first_string, first(value::float) AS first_value FROM strings WHERE time < now() GROUP BY time(10s) fill(0)`,
+		},
+
 		// No GROUP BY wildcards
 		{
 			stmt:    `SELECT value FROM cpu GROUP BY host`,
@@ -416,13 +519,49 @@ func TestSelectStatement_RewriteFields(t *testing.T) {
 		},
 
 		{
-			stmt:    `SELECT distinct(*) FROM strings`,
-			rewrite: `SELECT distinct(string::string) AS distinct_string, distinct(value::float) AS distinct_value FROM strings`,
+			stmt:    `SELECT distinct(*) FROM mem`,
+			rewrite: `SELECT distinct(value::float) AS distinct_value FROM mem`,
+		},
+
+		{
+			stmt:    `SELECT DISTINCT * FROM mem`,
+			rewrite: `SELECT distinct(value::float) AS distinct_value FROM mem`,
+		},
+
+		{
+			stmt: `SELECT DISTINCT * FROM cpu`,
+			err:  `aggregate function distinct() cannot be combined with other functions or fields`,
+		},
+
+		{
+			stmt: `SELECT distinct(*) FROM strings`,
+			err:  `aggregate function distinct() cannot be combined with other functions or fields`,
+		},
+
+		{
+			stmt: `SELECT distinct(*) FROM bools`,
+			err:  `aggregate function distinct() cannot be combined with other functions or fields`,
 		},
 
+		// Selectors that work on all types keep string and boolean fields.
 		{
-			stmt:    `SELECT distinct(*) FROM bools`,
-			rewrite: `SELECT distinct(bool::boolean) AS distinct_bool, distinct(value::float) AS distinct_value FROM bools`,
+			stmt:    `SELECT first(*) FROM bools`,
+			rewrite: `SELECT first(bool::boolean) AS first_bool, first(value::float) AS first_value FROM bools`,
+		},
+
+		{
+			stmt:    `SELECT last(*) FROM strings`,
+			rewrite: `SELECT last(string::string) AS last_string, last(value::float) AS last_value FROM strings`,
+		},
+
+		{
+			stmt:    `SELECT count(*) FROM bools`,
+			rewrite: `SELECT count(bool::boolean) AS count_bool, count(value::float) AS count_value FROM bools`,
+		},
+
+		{
+			stmt:    `SELECT count(*) FROM mixed`,
+			rewrite: `SELECT count(status::string) AS count_status, count(total::integer) AS count_total, count(value::float) AS count_value FROM mixed`,
 		},
 
 		// Wildcard function with some fields excluded.
@@ -436,6 +575,37 @@ func TestSelectStatement_RewriteFields(t *testing.T) {
 			rewrite: `SELECT mean(value::float) AS mean_value FROM bools`,
 		},
 
+		{
+			stmt:    `SELECT median(*) FROM strings`,
+			rewrite: `SELECT median(value::float) AS median_value FROM strings`,
+		},
+
+		{
+			stmt:    `SELECT median(*) FROM bools`,
+			rewrite: `SELECT median(value::float) AS median_value FROM bools`,
+		},
+
+		{
+			stmt:    `SELECT mode(*) FROM strings`,
+			rewrite: `SELECT mode(string::string) AS mode_string, mode(value::float) AS mode_value FROM strings`,
+		},
+
+		{
+			stmt:    `SELECT mode(*) FROM bools`,
+			rewrite: `SELECT mode(bool::boolean) AS mode_bool, mode(value::float) AS mode_value FROM bools`,
+		},
+
+		// Wildcard function with extra arguments.
+		{
+			stmt:    `SELECT percentile(*, 95) FROM cpu`,
+			rewrite: `SELECT percentile(value1::float, 95) AS percentile_value1, percentile(value2::integer, 95) AS percentile_value2 FROM cpu`,
+		},
+
+		{
+			stmt:    `SELECT percentile(*, 95) FROM strings`,
+			rewrite: `SELECT percentile(value::float, 95) AS percentile_value FROM strings`,
+		},
+
 		// Wildcard function with an alias.
 		{
 			stmt:    `SELECT mean(*) AS alias FROM cpu`,
@@ -465,6 +635,27 @@ func TestSelectStatement_RewriteFields(t *testing.T) {
 			rewrite: `SELECT mean::float FROM (SELECT mean(value1::float) FROM cpu GROUP BY host) GROUP BY host`,
 		},
 
+		// Aggregate over a field of a subquery
+		{
+			stmt:    `SELECT stddev(c) FROM (SELECT count(value1) AS c FROM cpu GROUP BY time(1m)) WHERE time >= now() - 1h`,
+			rewrite: `SELECT stddev(c::integer) FROM (SELECT count(value1::float) AS c FROM cpu GROUP BY time(1m)) WHERE time >= now() - 1h`,
+		},
+
+		{
+			stmt:    `SELECT spread(c) FROM (SELECT count(value1) AS c FROM cpu GROUP BY time(1m)) WHERE time >= now() - 1h`,
+			rewrite: `SELECT spread(c::integer) FROM (SELECT count(value1::float) AS c FROM cpu GROUP BY time(1m)) WHERE time >= now() - 1h`,
+		},
+
+		{
+			stmt:    `SELECT median(c) FROM (SELECT count(value1) AS c FROM cpu GROUP BY time(1m)) WHERE time >= now() - 1h`,
+			rewrite: `SELECT median(c::integer) FROM (SELECT count(value1::float) AS c FROM cpu GROUP BY time(1m)) WHERE time >= now() - 1h`,
+		},
+
+		{
+			stmt:    `SELECT mode(c) FROM (SELECT count(value1) AS c FROM cpu GROUP BY time(1m)) WHERE time >= now() - 1h`,
+			rewrite: `SELECT mode(c::integer) FROM (SELECT count(value1::float) AS c FROM cpu GROUP BY time(1m)) WHERE time >= now() - 1h`,
+		},
+
 		// Invalid queries that can't be rewritten should return an error (to
 		// avoid a panic in the query engine)
 		{
@@ -520,6 +711,21 @@ func TestSelectStatement_RewriteFields(t *testing.T) {
 					"value": influxql.Float,
 					"bool":  influxql.Boolean,
 				}
+			case "mem":
+				fields = map[string]influxql.DataType{
+					"value": influxql.Float,
+				}
+			case "overlap":
+				fields = map[string]influxql.DataType{
+					"value": influxql.Float,
+					"host":  influxql.String,
+				}
+			case "mixed":
+				fields = map[string]influxql.DataType{
+					"value":  influxql.Float,
+					"total":  influxql.Integer,
+					"status": influxql.String,
+				}
 			}
 			dimensions = map[string]struct{}{"host": struct{}{}, "region": struct{}{}}
 			return
@@ -545,6 +751,31 @@ func TestSelectStatement_RewriteFields(t *testing.T) {
 	}
 }
 
+// Ensure grouping by a tag does not require a schema lookup when there are no wildcards.
+func TestSelectStatement_RewriteFields_GroupByTag(t *testing.T) {
+	var ic IteratorCreator
+	ic.FieldDimensionsFn = func(m *influxql.Measurement) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error) {
+		t.Fatal("unexpected call to FieldDimensions")
+		return
+	}
+	ic.MapTypeFn = func(m *influxql.Measurement, field string) influxql.DataType {
+		switch field {
+		case "value":
+			return influxql.Float
+		case "host":
+			return influxql.Tag
+		}
+		return influxql.Unknown
+	}
+
+	stmt := MustParseSelectStatement(`SELECT mean(value) FROM cpu GROUP BY host`)
+	if rw, err := stmt.RewriteFields(&ic); err != nil {
+		t.Fatal(err)
+	} else if exp, got := `SELECT mean(value::float) FROM cpu GROUP BY host`, rw.String(); exp != got {
+		t.Fatalf("unexpected rewrite:\n\nexp=%s\n\ngot=%s\n\n", exp, got)
+	}
+}
+
 // Test SELECT statement regex conditions rewrite.
 func TestSelectStatement_RewriteRegexConditions(t *testing.T) {
 	var tests = []struct {
@@ -835,7 +1066,46 @@ func TestSelectStatement_IsSimpleDerivative(t *testing.T) {
 	}
 }
 
-// Ensure binary expression names can be evaluated.
+// Ensure function calls are counted across nested calls and subqueries.
+func TestSelectStatement_TotalFunctionCallCount(t *testing.T) {
+	for i, tt := range []struct {
+		stmt string
+		n    int
+	}{
+		{stmt: `SELECT value FROM cpu`, n: 0},
+		{stmt: `SELECT count(value), sum(value) FROM cpu`, n: 2},
+		{stmt: `SELECT derivative(mean(value), 1s) FROM cpu WHERE time < now() GROUP BY time(1m)`, n: 2},
+		{stmt: `SELECT mean(value) + max(value) FROM cpu`, n: 2},
+		{stmt: `SELECT max(value) FROM (SELECT mean(value) AS value FROM cpu GROUP BY host)`, n: 2},
+		{stmt: `SELECT value FROM (SELECT top(value, 2) FROM (SELECT max(value) AS value FROM cpu GROUP BY host))`, n: 2},
+	} {
+		stmt := MustParseSelectStatement(tt.stmt)
+		if n := stmt.TotalFunctionCallCount(); n != tt.n {
+			t.Errorf("%d. %q: unexpected function call count: exp=%d got=%d", i, tt.stmt, tt.n, n)
+		}
+	}
+}
+
+// Ensure the largest top() and bottom() limit is found across subqueries.
+func TestSelectStatement_MaxTopBottomLimit(t *testing.T) {
+	for i, tt := range []struct {
+		stmt string
+		n    int64
+	}{
+		{stmt: `SELECT value FROM cpu`, n: 0},
+		{stmt: `SELECT top(value, 3) FROM cpu`, n: 3},
+		{stmt: `SELECT top(value, host, 5) FROM cpu`, n: 5},
+		{stmt: `SELECT bottom(value, 4) FROM cpu`, n: 4},
+		{stmt: `SELECT max(value) FROM (SELECT top(value, 10) AS value FROM cpu)`, n: 10},
+		{stmt: `SELECT top(value, 2) FROM (SELECT bottom(value, 7) AS value FROM (SELECT top(value, 6) AS value FROM cpu))`, n: 7},
+	} {
+		stmt := MustParseSelectStatement(tt.stmt)
+		if n := stmt.MaxTopBottomLimit(); n != tt.n {
+			t.Errorf("%d. %q: unexpected limit: exp=%d got=%d", i, tt.stmt, tt.n, n)
+		}
+	}
+}
+
 func TestBinaryExprName(t *testing.T) {
 	for i, tt := range []struct {
 		expr string
@@ -1207,6 +1477,32 @@ func TestEvalType(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: `float and integer across sources`,
+			in:   `value`,
+			typ:  influxql.Float,
+			data: EvalFixture{
+				"cpu": map[string]influxql.DataType{
+					"value": influxql.Float,
+				},
+				"mem": map[string]influxql.DataType{
+					"value": influxql.Integer,
+				},
+			},
+		},
+		{
+			name: `string and float across sources`,
+			in:   `value`,
+			typ:  influxql.Float,
+			data: EvalFixture{
+				"cpu": map[string]influxql.DataType{
+					"value": influxql.String,
+				},
+				"mem": map[string]influxql.DataType{
+					"value": influxql.Float,
+				},
+			},
+		},
 		{
 			name: `count() with a float`,
 			in:   `count(value)`,
@@ -1217,6 +1513,16 @@ func TestEvalType(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: `count() of distinct strings`,
+			in:   `count(distinct(value))`,
+			typ:  influxql.Integer,
+			data: EvalFixture{
+				"cpu": map[string]influxql.DataType{
+					"value": influxql.String,
+				},
+			},
+		},
 		{
 			name: `mean() with an integer`,
 			in:   `mean(value)`,
@@ -1320,6 +1320,7 @@ func TestIterator_EncodeDecode(t *testing.T) {
 type IteratorCreator struct {
 	CreateIteratorFn  func(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error)
 	FieldDimensionsFn func(m *influxql.Measurement) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error)
+	MapTypeFn         func(m *influxql.Measurement, field string) influxql.DataType
 }
 
 func (ic *IteratorCreator) CreateIterator(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error) {
@@ -1331,6 +1332,10 @@ func (ic *IteratorCreator) FieldDimensions(m *influxql.Measurement) (fields map[
 }
 
 func (ic *IteratorCreator) MapType(m *influxql.Measurement, field string) influxql.DataType {
+	if ic.MapTypeFn != nil {
+		return ic.MapTypeFn(m, field)
+	}
+
 	f, d, err := ic.FieldDimensions(m)
 	if err != nil {
 		return influxql.Unknown
@@ -21,10 +21,20 @@ const (
 	DateTimeFormat = "2006-01-02 15:04:05.999999"
 )
 
+// maxExprDepth is the maximum number of parentheses, function calls and
+// subqueries that an expression may be nested within. It is fixed rather
+// than configurable because queries are parsed before any server
+// configuration applies, including by the CLI and when stored continuous
+// queries are re-parsed. It is set well above what written or generated
+// queries use so that existing continuous queries keep parsing, while still
+// being far short of exhausting the stack.
+const maxExprDepth = 100
+
 // Parser represents an InfluxQL parser.
 type Parser struct {
 	s      *bufScanner
 	params map[string]interface{}
+	depth  int
 }
 
 // NewParser returns a new instance of Parser.
@@ -2055,6 +2065,9 @@ func (p *Parser) parseSource(subqueries bool) (Source, error) {
 	// Parse the subquery if we are in a query that allows them as a source.
 	if m.Regex == nil && subqueries {
 		if tok, _, _ := p.scanIgnoreWhitespace(); tok == LPAREN {
+			p.depth++
+			defer func() { p.depth-- }()
+
 			if err := p.parseTokens([]Token{SELECT}); err != nil {
 				return nil, err
 			}
@@ -2476,8 +2489,18 @@ func (p *Parser) ParseExpr() (Expr, error) {
 
 // parseUnaryExpr parses an non-binary expression.
 func (p *Parser) parseUnaryExpr() (Expr, error) {
+	// Refuse to descend any further once the nesting limit is exceeded so
+	// a deeply nested expression cannot exhaust the stack.
+	if p.depth > maxExprDepth {
+		_, pos, _ := p.scanIgnoreWhitespace()
+		return nil, &ParseError{Message: fmt.Sprintf("exceeded maximum expression depth of %d", maxExprDepth), Pos: pos}
+	}
+
 	// If the first token is a LPAREN then parse it as its own grouped expression.
 	if tok, _, _ := p.scanIgnoreWhitespace(); tok == LPAREN {
+		p.depth++
+		defer func() { p.depth-- }()
+
 		expr, err := p.ParseExpr()
 		if err != nil {
 			return nil, err
@@ -2515,7 +2538,11 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 			return p.parseCall("distinct")
 		} else if tok0 == WS {
 			tok1, pos, lit := p.scanIgnoreWhitespace()
-			if tok1 != IDENT {
+			if tok1 == MUL {
+				// DISTINCT * is parsed as distinct(*) so the wildcard is
+				// expanded by RewriteFields like any other call.
+				return &Call{Name: "distinct", Args: []Expr{&Wildcard{}}}, nil
+			} else if tok1 != IDENT {
 				return nil, newParseError(tokstr(tok1, lit), []string{"identifier"}, pos)
 			}
 			return &Distinct{Val: lit}, nil
@@ -2666,6 +2693,9 @@ func (p *Parser) parseRegex() (*RegexLiteral, error) {
 // parseCall parses a function call.
 // This function assumes the function name and LPAREN have been consumed.
 func (p *Parser) parseCall(name string) (*Call, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+
 	name = strings.ToLower(name)
 
 	// Parse first function argument if one exists.
@@ -205,6 +205,29 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// field with the same name as a function
+		{
+			s: `SELECT mean FROM cpu`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: true,
+				Fields: []*influxql.Field{
+					{Expr: &influxql.VarRef{Val: "mean"}},
+				},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+			},
+		},
+
+		{
+			s: `SELECT mean(mean) FROM cpu`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: false,
+				Fields: []*influxql.Field{
+					{Expr: &influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "mean"}}}},
+				},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+			},
+		},
+
 		// derivative
 		{
 			s: `SELECT derivative(field1, 1h) FROM myseries;`,
@@ -233,6 +256,29 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		{
+			s: fmt.Sprintf(`SELECT derivative(mean(field1), 1m) FROM myseries WHERE time > '%s' GROUP BY time(1m)`, now.UTC().Format(time.RFC3339Nano)),
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: false,
+				Fields: []*influxql.Field{
+					{Expr: &influxql.Call{
+						Name: "derivative",
+						Args: []influxql.Expr{
+							&influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "field1"}}},
+							&influxql.DurationLiteral{Val: time.Minute},
+						},
+					}},
+				},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "myseries"}},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.GT,
+					LHS: &influxql.VarRef{Val: "time"},
+					RHS: &influxql.StringLiteral{Val: now.UTC().Format(time.RFC3339Nano)},
+				},
+				Dimensions: []*influxql.Dimension{{Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: time.Minute}}}}},
+			},
+		},
+
 		{
 			s: `SELECT derivative(field1, 1h) / derivative(field2, 1h) FROM myseries`,
 			stmt: &influxql.SelectStatement{
@@ -726,6 +772,17 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		{
+			s: `select percentile("field1", 99.9) from cpu`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: false,
+				Fields: []*influxql.Field{
+					{Expr: &influxql.Call{Name: "percentile", Args: []influxql.Expr{&influxql.VarRef{Val: "field1"}, &influxql.NumberLiteral{Val: 99.9}}}},
+				},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+			},
+		},
+
 		// select top statements
 		{
 			s: `select top("field1", 2) from cpu`,
@@ -796,6 +853,17 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		{
+			s: `select distinct * from network`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: false,
+				Fields: []*influxql.Field{
+					{Expr: &influxql.Call{Name: "distinct", Args: []influxql.Expr{&influxql.Wildcard{}}}},
+				},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "network"}},
+			},
+		},
+
 		{
 			s: `select count(distinct field3) from metrics`,
 			stmt: &influxql.SelectStatement{
@@ -2567,7 +2635,9 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SELECT top(field1,5,'server',2) FROM myseries`, err: `only fields or tags are allowed in top(), found 5`},
 		{s: `SELECT top(field1,max(foo),'server',2) FROM myseries`, err: `only fields or tags are allowed in top(), found max(foo)`},
 		{s: `SELECT top(value, 10) + count(value) FROM myseries`, err: `cannot use top() inside of a binary expression`},
-		{s: `SELECT top(max(value), 10) FROM myseries`, err: `only fields or tags are allowed in top(), found max(value)`},
+		{s: `SELECT top(max(value), 10) FROM myseries`, err: `nested aggregate max is not allowed in top()`},
+		{s: `SELECT top(mean(value), 3) FROM myseries`, err: `nested aggregate mean is not allowed in top()`},
+		{s: `SELECT top(now(), 3) FROM myseries`, err: `only fields or tags are allowed in top(), found now()`},
 		{s: `SELECT bottom() FROM myseries`, err: `invalid number of arguments for bottom, expected at least 2, got 0`},
 		{s: `SELECT bottom(field1) FROM myseries`, err: `invalid number of arguments for bottom, expected at least 2, got 1`},
 		{s: `SELECT bottom(field1,foo) FROM myseries`, err: `expected integer as last argument in bottom(), found foo`},
@@ -2575,11 +2645,17 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SELECT bottom(field1,5,'server',2) FROM myseries`, err: `only fields or tags are allowed in bottom(), found 5`},
 		{s: `SELECT bottom(field1,max(foo),'server',2) FROM myseries`, err: `only fields or tags are allowed in bottom(), found max(foo)`},
 		{s: `SELECT bottom(value, 10) + count(value) FROM myseries`, err: `cannot use bottom() inside of a binary expression`},
-		{s: `SELECT bottom(max(value), 10) FROM myseries`, err: `only fields or tags are allowed in bottom(), found max(value)`},
+		{s: `SELECT bottom(max(value), 10) FROM myseries`, err: `nested aggregate max is not allowed in bottom()`},
+		{s: `SELECT bottom(mean(value), 3) FROM myseries`, err: `nested aggregate mean is not allowed in bottom()`},
+		{s: `SELECT bottom(now(), 3) FROM myseries`, err: `only fields or tags are allowed in bottom(), found now()`},
 		{s: `SELECT percentile() FROM myseries`, err: `invalid number of arguments for percentile, expected 2, got 0`},
 		{s: `SELECT percentile(field1) FROM myseries`, err: `invalid number of arguments for percentile, expected 2, got 1`},
 		{s: `SELECT percentile(field1, foo) FROM myseries`, err: `expected float argument in percentile()`},
 		{s: `SELECT percentile(max(field1), 75) FROM myseries`, err: `expected field argument in percentile()`},
+		{s: `SELECT sample() FROM myseries`, err: `invalid number of arguments for sample, expected 2, got 0`},
+		{s: `SELECT sample(field1) FROM myseries`, err: `invalid number of arguments for sample, expected 2, got 1`},
+		{s: `SELECT sample(field1, 2.5) FROM myseries`, err: `expected integer argument in sample()`},
+		{s: `SELECT sample(max(field1), 2) FROM myseries`, err: `expected field argument in sample()`},
 		{s: `SELECT field1 FROM myseries OFFSET`, err: `found EOF, expected integer at line 1, char 36`},
 		{s: `SELECT field1 FROM myseries OFFSET 10.5`, err: `found 10.5, expected integer at line 1, char 36`},
 		{s: `SELECT field1 FROM myseries ORDER`, err: `found EOF, expected BY at line 1, char 35`},
@@ -2590,6 +2666,7 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SELECT field1 FROM myseries ORDER BY time, field1`, err: `only ORDER BY time supported at this time`},
 		{s: `SELECT field1 AS`, err: `found EOF, expected identifier at line 1, char 18`},
 		{s: `SELECT field1 FROM foo group by time(1s)`, err: `GROUP BY requires at least one aggregate function`},
+		{s: `SELECT field1 FROM foo group by time(1s) fill(0)`, err: `GROUP BY requires at least one aggregate function`},
 		{s: `SELECT field1 FROM foo fill(none)`, err: `fill(none) must be used with a function`},
 		{s: `SELECT field1 FROM foo fill(linear)`, err: `fill(linear) must be used with a function`},
 		{s: `SELECT count(value), value FROM foo`, err: `mixing aggregate and non-aggregate queries is not supported`},
@@ -2601,6 +2678,7 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SELECT count(value) FROM foo group by 'time'`, err: `only time and tag dimensions allowed`},
 		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time()`, err: `time dimension expected 1 or 2 arguments`},
 		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time(b)`, err: `time dimension must have duration argument`},
+		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time(0s)`, err: `time dimension must have non-zero duration`},
 		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time(1s), time(2s)`, err: `multiple time dimensions not allowed`},
 		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time(1s, b)`, err: `time dimension offset must be duration or now()`},
 		{s: `SELECT field1 FROM 12`, err: `found 12, expected identifier at line 1, char 20`},
@@ -2625,6 +2703,8 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SELECT derivative(max()) FROM myseries where time < now() and time > now() - 1d group by time(1h)`, err: `invalid number of arguments for max, expected 1, got 0`},
 		{s: `SELECT derivative(percentile(value)) FROM myseries where time < now() and time > now() - 1d group by time(1h)`, err: `invalid number of arguments for percentile, expected 2, got 1`},
 		{s: `SELECT derivative(mean(value), 1h) FROM myseries where time < now() and time > now() - 1d`, err: `derivative aggregate requires a GROUP BY interval`},
+		{s: `SELECT derivative(derivative(value)) FROM myseries where time < now() and time > now() - 1d group by time(1h)`, err: `nested transformation derivative is not allowed in derivative()`},
+		{s: `SELECT derivative(difference(mean(value))) FROM myseries where time < now() and time > now() - 1d group by time(1h)`, err: `nested transformation difference is not allowed in derivative()`},
 		{s: `SELECT min(derivative) FROM (SELECT derivative(mean(value), 1h) FROM myseries) where time < now() and time > now() - 1d`, err: `derivative aggregate requires a GROUP BY interval`},
 		{s: `SELECT non_negative_derivative(), field1 FROM myseries`, err: `mixing aggregate and non-aggregate queries is not supported`},
 		{s: `select non_negative_derivative() from myseries`, err: `invalid number of arguments for non_negative_derivative, expected at least 1 but no more than 2, got 0`},
@@ -2646,6 +2726,7 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SELECT moving_average(), field1 FROM myseries`, err: `mixing aggregate and non-aggregate queries is not supported`},
 		{s: `SELECT moving_average() from myseries`, err: `invalid number of arguments for moving_average, expected 2, got 0`},
 		{s: `SELECT moving_average(value) FROM myseries`, err: `invalid number of arguments for moving_average, expected 2, got 1`},
+		{s: `SELECT moving_average(value, 5m) FROM myseries`, err: `moving_average window must be an integer number of points`},
 		{s: `SELECT moving_average(value, 2) FROM myseries group by time(1h)`, err: `aggregate function required inside the call to moving_average`},
 		{s: `SELECT moving_average(top(value), 2) FROM myseries where time < now() and time > now() - 1d group by time(1h)`, err: `invalid number of arguments for top, expected at least 2, got 1`},
 		{s: `SELECT moving_average(bottom(value), 2) FROM myseries where time < now() and time > now() - 1d group by time(1h)`, err: `invalid number of arguments for bottom, expected at least 2, got 1`},
@@ -3245,6 +3326,33 @@ func TestParser_ParseExpr(t *testing.T) {
 	}
 }
 
+// Ensure the parser limits how deeply expressions can be nested.
+func TestParser_ParseStatement_MaxExprDepth(t *testing.T) {
+	nest := func(open, inner, close string, n int) string {
+		return strings.Repeat(open, n) + inner + strings.Repeat(close, n)
+	}
+
+	for i, tt := range []struct {
+		s   string
+		err string
+	}{
+		{s: `SELECT ` + nest("(", "value", ")", 100) + ` FROM cpu`},
+		{s: `SELECT ` + nest("(", "value", ")", 101) + ` FROM cpu`, err: `exceeded maximum expression depth of 100 at line 1, char 109`},
+		{s: `SELECT ` + nest("(", "max(value)", ")", 99) + ` FROM cpu`},
+		{s: `SELECT ` + nest("(", "max(value)", ")", 100) + ` FROM cpu`, err: `exceeded maximum expression depth of 100 at line 1, char 112`},
+		{s: `SELECT value FROM cpu WHERE ` + nest("(", "host = 'a'", ")", 100)},
+		{s: `SELECT value FROM cpu WHERE ` + nest("(", "host = 'a'", ")", 101), err: `exceeded maximum expression depth of 100 at line 1, char 130`},
+		{s: `SELECT ` + nest("(", "value", ")", 1000000) + ` FROM cpu`, err: `exceeded maximum expression depth of 100 at line 1, char 109`},
+		{s: nest("SELECT value FROM (", "SELECT value FROM cpu", ")", 100)},
+		{s: nest("SELECT value FROM (", "SELECT value FROM cpu", ")", 101), err: `exceeded maximum expression depth of 100 at line 1, char 1927`},
+	} {
+		_, err := influxql.NewParser(strings.NewReader(tt.s)).ParseStatement()
+		if errstring(err) != tt.err {
+			t.Errorf("%d. unexpected error: exp=%s got=%s", i, tt.err, err)
+		}
+	}
+}
+
 // Ensure a time duration can be parsed.
 func TestParseDuration(t *testing.T) {
 	var tests = []struct {
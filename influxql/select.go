@@ -51,9 +51,15 @@ func buildIterators(stmt *SelectStatement, ic IteratorCreator, opt IteratorOptio
 		return nil, errors.New("cannot select fields when selecting multiple aggregates")
 	}
 
-	// Determine auxiliary fields to be selected.
+	// Determine auxiliary fields to be selected. Multiple references to the
+	// same field and type share a single auxiliary field.
 	opt.Aux = make([]VarRef, 0, len(info.refs))
+	seen := make(map[VarRef]struct{}, len(info.refs))
 	for ref := range info.refs {
+		if _, ok := seen[*ref]; ok {
+			continue
+		}
+		seen[*ref] = struct{}{}
 		opt.Aux = append(opt.Aux, *ref)
 	}
 	sort.Sort(VarRefs(opt.Aux))
@@ -588,6 +594,15 @@ func (b *exprIteratorBuilder) buildCallIterator(expr *Call) (Iterator, error) {
 		return newBottomIterator(input, b.opt, int(n.Val), b.writeMode)
 	}
 
+	// Reject numeric aggregates on fields already known to be non-numeric so
+	// the error names the field rather than an iterator type.
+	switch expr.Name {
+	case "sum", "mean", "median", "stddev", "spread", "percentile":
+		if ref, ok := expr.Args[0].(*VarRef); ok && (ref.Type == String || ref.Type == Boolean) {
+			return nil, fmt.Errorf("cannot use %s on field of type %s: %s", expr.Name, ref.Type, ref.Val)
+		}
+	}
+
 	itr, err := func() (Iterator, error) {
 		switch expr.Name {
 		case "count":
@@ -636,12 +651,9 @@ func (b *exprIteratorBuilder) buildCallIterator(expr *Call) (Iterator, error) {
 			if err != nil {
 				return nil, err
 			}
-			var percentile float64
-			switch arg := expr.Args[1].(type) {
-			case *NumberLiteral:
-				percentile = arg.Val
-			case *IntegerLiteral:
-				percentile = float64(arg.Val)
+			percentile, err := numericArg(expr, 1, true)
+			if err != nil {
+				return nil, err
 			}
 			return newPercentileIterator(input, opt, percentile)
 		default:
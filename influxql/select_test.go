@@ -223,6 +223,44 @@ func TestSelect_Distinct_Boolean(t *testing.T) {
 	}
 }
 
+// Ensure a SELECT count(distinct()) query returns integers for a string field.
+func TestSelect_CountDistinct_String(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		if m.Name != "cpu" {
+			t.Fatalf("unexpected source: %s", m.Name)
+		}
+		return influxql.Iterators{
+			&StringIterator{Points: []influxql.StringPoint{
+				{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: "a"},
+				{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 1 * Second, Value: "b"},
+			}},
+			&StringIterator{Points: []influxql.StringPoint{
+				{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 5 * Second, Value: "b"},
+			}},
+			&StringIterator{Points: []influxql.StringPoint{
+				{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 9 * Second, Value: "a"},
+				{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 10 * Second, Value: "c"},
+				{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 11 * Second, Value: "c"},
+			}},
+		}.Merge(opt)
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT count(distinct(value)) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a, err := Iterators(itrs).ReadAll(); err != nil {
+		t.Fatalf("unexpected point: %s", err)
+	} else if !deep.Equal(a, [][]influxql.Point{
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 2, Aggregated: 2}},
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 1, Aggregated: 1}},
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 0 * Second, Value: 1, Aggregated: 1}},
+	}) {
+		t.Errorf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
 // Ensure a SELECT mean() query can be executed.
 func TestSelect_Mean_Float(t *testing.T) {
 	var ic IteratorCreator
@@ -342,6 +380,25 @@ func TestSelect_Mean_String(t *testing.T) {
 	}
 }
 
+// Ensure a SELECT mean() query on a field typed as a string names the field.
+func TestSelect_Mean_StringField(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		t.Fatal("unexpected call to CreateIterator")
+		return nil, nil
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT mean(value::string) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`), &ic, nil)
+	if err == nil || err.Error() != "cannot use mean on field of type string: value" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if itrs != nil {
+		influxql.Iterators(itrs).Close()
+	}
+}
+
 // Ensure a SELECT mean() query cannot be executed on booleans.
 func TestSelect_Mean_Boolean(t *testing.T) {
 	var ic IteratorCreator
@@ -363,6 +420,76 @@ func TestSelect_Mean_Boolean(t *testing.T) {
 	}
 }
 
+// Ensure a SELECT sum() query on a field typed as a boolean names the field.
+func TestSelect_Sum_BooleanField(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		t.Fatal("unexpected call to CreateIterator")
+		return nil, nil
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT sum(value::boolean) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`), &ic, nil)
+	if err == nil || err.Error() != "cannot use sum on field of type boolean: value" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if itrs != nil {
+		influxql.Iterators(itrs).Close()
+	}
+}
+
+// Ensure a numeric fill value still fills fields of every type expanded from a wildcard.
+func TestSelect_First_Wildcard_NumberFill(t *testing.T) {
+	var ic IteratorCreator
+	ic.FieldDimensionsFn = func(m *influxql.Measurement) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error) {
+		return map[string]influxql.DataType{
+			"status": influxql.String,
+			"value":  influxql.Float,
+		}, nil, nil
+	}
+	ic.CreateIteratorFn = func(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		if m.Name != "cpu" {
+			t.Fatalf("unexpected source: %s", m.Name)
+		}
+		switch opt.Expr.(*influxql.Call).Args[0].(*influxql.VarRef).Val {
+		case "status":
+			return influxql.NewCallIterator(&StringIterator{Points: []influxql.StringPoint{
+				{Name: "cpu", Time: 0 * Second, Value: "a"},
+			}}, opt)
+		case "value":
+			return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+				{Name: "cpu", Time: 0 * Second, Value: 1},
+			}}, opt)
+		}
+		return nil, fmt.Errorf("unexpected expression: %s", opt.Expr)
+	}
+
+	stmt, err := MustParseSelectStatement(`SELECT first(*) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:20Z' GROUP BY time(10s) fill(0)`).RewriteFields(&ic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(stmt, &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a, err := Iterators(itrs).ReadAll(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !deep.Equal(a, [][]influxql.Point{
+		{
+			&influxql.StringPoint{Name: "cpu", Time: 0 * Second, Value: "a", Aggregated: 1},
+			&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 1, Aggregated: 1},
+		},
+		{
+			&influxql.StringPoint{Name: "cpu", Time: 10 * Second, Value: ""},
+			&influxql.FloatPoint{Name: "cpu", Time: 10 * Second, Value: 0},
+		},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
 // Ensure a SELECT median() query can be executed.
 func TestSelect_Median_Float(t *testing.T) {
 	var ic IteratorCreator
@@ -2055,6 +2182,111 @@ func TestSelect_Raw(t *testing.T) {
 	}
 }
 
+// Ensure repeated references to a field share one auxiliary field.
+func TestSelect_Raw_SharedAux(t *testing.T) {
+	for _, tt := range []struct {
+		s   string
+		aux []influxql.VarRef
+	}{
+		{
+			s:   `SELECT value::float, value::float + 1 FROM cpu`,
+			aux: []influxql.VarRef{{Val: "value", Type: influxql.Float}},
+		},
+		{
+			s:   `SELECT value::float, value::integer FROM cpu`,
+			aux: []influxql.VarRef{{Val: "value", Type: influxql.Float}, {Val: "value", Type: influxql.Integer}},
+		},
+		{
+			s:   `SELECT value::float, value::float FROM cpu`,
+			aux: []influxql.VarRef{{Val: "value", Type: influxql.Float}},
+		},
+	} {
+		var ic IteratorCreator
+		ic.CreateIteratorFn = func(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+			if !reflect.DeepEqual(opt.Aux, tt.aux) {
+				t.Fatalf("%s: unexpected aux fields: %s", tt.s, spew.Sdump(opt.Aux))
+			}
+			return &FloatIterator{}, nil
+		}
+
+		itrs, err := influxql.Select(MustParseSelectStatement(tt.s), &ic, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.s, err)
+		}
+		influxql.Iterators(itrs).Close()
+	}
+}
+
+// Ensure a field selected twice is kept as two output columns read from one
+// auxiliary field.
+func TestSelect_Raw_DuplicateField(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		if m.Name != "cpu" {
+			t.Fatalf("unexpected source: %s", m.Name)
+		}
+		return &FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Time: 0 * Second, Aux: []interface{}{float64(1)}},
+			{Name: "cpu", Time: 5 * Second, Aux: []interface{}{float64(2)}},
+		}}, nil
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT value::float, value::float FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z'`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a, err := Iterators(itrs).ReadAll(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !deep.Equal(a, [][]influxql.Point{
+		{
+			&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 1},
+			&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 1},
+		},
+		{
+			&influxql.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 2},
+			&influxql.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 2},
+		},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
+// Ensure a raw SELECT statement grouped by a tag can be executed.
+func TestSelect_Raw_GroupByTag(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		if m.Name != "cpu" {
+			t.Fatalf("unexpected source: %s", m.Name)
+		}
+		if !reflect.DeepEqual(opt.Dimensions, []string{"host"}) {
+			t.Fatalf("unexpected dimensions: %v", opt.Dimensions)
+		} else if !opt.Interval.IsZero() {
+			t.Fatalf("unexpected interval: %v", opt.Interval)
+		} else if !reflect.DeepEqual(opt.Aux, []influxql.VarRef{{Val: "value", Type: influxql.Float}}) {
+			t.Fatalf("unexpected aux fields: %s", spew.Sdump(opt.Aux))
+		}
+		return &FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 0, Aux: []interface{}{float64(1)}},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 5, Aux: []interface{}{float64(2)}},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 1, Aux: []interface{}{float64(3)}},
+		}}, nil
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT value::float FROM cpu GROUP BY host`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a, err := Iterators(itrs).ReadAll(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !deep.Equal(a, [][]influxql.Point{
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0, Value: 1}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 5, Value: 2}},
+		{&influxql.FloatPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 1, Value: 3}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
 // Ensure a SELECT binary expr queries can be executed as floats.
 func TestSelect_BinaryExpr_Float(t *testing.T) {
 	var ic IteratorCreator
@@ -2267,6 +2499,33 @@ func TestSelect_BinaryExpr_Float(t *testing.T) {
 				{&influxql.FloatPoint{Name: "cpu", Time: 9 * Second, Value: 1}},
 			},
 		},
+		{
+			Name:      "parenthesized binary expression",
+			Statement: `SELECT (value + 2) * 2 FROM cpu`,
+			Points: [][]influxql.Point{
+				{&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 44}},
+				{&influxql.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 24}},
+				{&influxql.FloatPoint{Name: "cpu", Time: 9 * Second, Value: 42}},
+			},
+		},
+		{
+			Name:      "nested parenthesized binary expression",
+			Statement: `SELECT ((value + 2) * (1 + 1)) / 2 FROM cpu`,
+			Points: [][]influxql.Point{
+				{&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 22}},
+				{&influxql.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 12}},
+				{&influxql.FloatPoint{Name: "cpu", Time: 9 * Second, Value: 21}},
+			},
+		},
+		{
+			Name:      "unary negation",
+			Statement: `SELECT -value FROM cpu`,
+			Points: [][]influxql.Point{
+				{&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: -20}},
+				{&influxql.FloatPoint{Name: "cpu", Time: 5 * Second, Value: -10}},
+				{&influxql.FloatPoint{Name: "cpu", Time: 9 * Second, Value: -19}},
+			},
+		},
 	} {
 		stmt, err := MustParseSelectStatement(test.Statement).RewriteFields(&ic)
 		if err != nil {
@@ -2783,6 +3042,80 @@ func TestSelect_BinaryExpr_NilValues(t *testing.T) {
 	}
 }
 
+// Ensure a SELECT binary expr between an aggregate and a literal can be
+// executed with the literal on either side.
+func TestSelect_BinaryExpr_Aggregate(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		if m.Name != "cpu" {
+			t.Fatalf("unexpected source: %s", m.Name)
+		}
+		return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Time: 0 * Second, Value: 20},
+			{Name: "cpu", Time: 5 * Second, Value: 10},
+			{Name: "cpu", Time: 9 * Second, Value: 10},
+		}}, opt)
+	}
+
+	for _, test := range []struct {
+		Name      string
+		Statement string
+		Points    [][]influxql.Point
+	}{
+		{
+			Name:      "rhs binary multiply",
+			Statement: `SELECT sum(value) * 2 FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:10Z' GROUP BY time(10s)`,
+			Points: [][]influxql.Point{
+				{&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 80, Aggregated: 3}},
+			},
+		},
+		{
+			Name:      "lhs binary multiply",
+			Statement: `SELECT 2 * sum(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:10Z' GROUP BY time(10s)`,
+			Points: [][]influxql.Point{
+				{&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 80, Aggregated: 3}},
+			},
+		},
+		{
+			Name:      "rhs binary division",
+			Statement: `SELECT sum(value) / 2 FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:10Z' GROUP BY time(10s)`,
+			Points: [][]influxql.Point{
+				{&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 20, Aggregated: 3}},
+			},
+		},
+		{
+			Name:      "lhs binary division",
+			Statement: `SELECT 80 / sum(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:10Z' GROUP BY time(10s)`,
+			Points: [][]influxql.Point{
+				{&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 2, Aggregated: 3}},
+			},
+		},
+		{
+			Name:      "parenthesized aggregate",
+			Statement: `SELECT (sum(value) + 10) * 2 FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:10Z' GROUP BY time(10s)`,
+			Points: [][]influxql.Point{
+				{&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 100, Aggregated: 3}},
+			},
+		},
+		{
+			Name:      "unary negation",
+			Statement: `SELECT -sum(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:10Z' GROUP BY time(10s)`,
+			Points: [][]influxql.Point{
+				{&influxql.FloatPoint{Name: "cpu", Time: 0 * Second, Value: -40, Aggregated: 3}},
+			},
+		},
+	} {
+		itrs, err := influxql.Select(MustParseSelectStatement(test.Statement), &ic, nil)
+		if err != nil {
+			t.Errorf("%s: parse error: %s", test.Name, err)
+		} else if a, err := Iterators(itrs).ReadAll(); err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.Name, err)
+		} else if !deep.Equal(a, test.Points) {
+			t.Errorf("%s: unexpected points: %s", test.Name, spew.Sdump(a))
+		}
+	}
+}
+
 // Ensure a SELECT (...) query can be executed.
 func TestSelect_ParenExpr(t *testing.T) {
 	var ic IteratorCreator
@@ -3724,6 +4057,45 @@ func TestSelect_CumulativeSum_Duplicate_Integer(t *testing.T) {
 	}
 }
 
+func TestSelect_CumulativeSum_Count_GroupByTag(t *testing.T) {
+	var ic IteratorCreator
+	ic.CreateIteratorFn = func(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+		if m.Name != "cpu" {
+			t.Fatalf("unexpected source: %s", m.Name)
+		}
+		if !reflect.DeepEqual(opt.Expr, MustParseExpr(`count(value)`)) {
+			t.Fatalf("unexpected expr: %s", spew.Sdump(opt.Expr))
+		}
+
+		return influxql.NewCallIterator(&FloatIterator{Points: []influxql.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 20},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 5 * Second, Value: 10},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 12 * Second, Value: 19},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 25 * Second, Value: 3},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 26 * Second, Value: 4},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 27 * Second, Value: 5},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 1 * Second, Value: 10},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 21 * Second, Value: 11},
+		}}, opt)
+	}
+
+	// Execute selection.
+	itrs, err := influxql.Select(MustParseSelectStatement(`SELECT cumulative_sum(count(value)) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z' GROUP BY time(10s), host fill(none)`), &ic, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if a, err := Iterators(itrs).ReadAll(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !deep.Equal(a, [][]influxql.Point{
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 2}},
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 3}},
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 20 * Second, Value: 6}},
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 0 * Second, Value: 1}},
+		{&influxql.IntegerPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 20 * Second, Value: 2}},
+	}) {
+		t.Fatalf("unexpected points: %s", spew.Sdump(a))
+	}
+}
+
 func TestSelect_HoltWinters_GroupBy_Agg(t *testing.T) {
 	var ic IteratorCreator
 	ic.CreateIteratorFn = func(m *influxql.Measurement, opt influxql.IteratorOptions) (influxql.Iterator, error) {